@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// TTL_JITTER 是抖动范围,避免批量写入的缓存同时过期造成雪崩;
+// 可通过配置文件的 cache.jitter 覆盖
+var TTL_JITTER = 60 * time.Second
+
+// 允许缓存的路径前缀
+var cacheablePathPrefixes = []string{
+	"/3/movie/",
+	"/3/tv/",
+	"/3/search/",
+	"/3/trending/",
+	"/3/configuration",
+}
+
+// 不允许缓存的路径前缀,即使命中上面的规则也要跳过(鉴权敏感)
+var uncacheablePathPrefixes = []string{
+	"/3/account/",
+}
+
+// CacheStatus 描述一次 Get 调用相对于 soft/hard expiry 的结果
+type CacheStatus int
+
+const (
+	// CacheMiss 表示未命中,或条目已超过 HardExpiry,需要完整回源
+	CacheMiss CacheStatus = iota
+	// CacheFresh 表示命中且未过 SoftExpiry,可以直接返回
+	CacheFresh
+	// CacheStale 表示已过 SoftExpiry 但未过 HardExpiry,
+	// 调用方应立即返回这份数据,同时考虑触发后台刷新
+	CacheStale
+)
+
+// ICache 定义缓存后端需要实现的能力,使 handler 可以在内存、Redis、
+// Memcached 等实现之间自由切换而无需感知具体存储细节
+type ICache interface {
+	// Enable 返回该缓存后端是否启用
+	Enable() bool
+	// TTL 返回该后端的默认缓存时长
+	TTL() time.Duration
+	// IsAllow 决定某次响应是否允许被缓存
+	IsAllow(req *http.Request, statusCode int, body []byte) bool
+	// Get 读取缓存,返回数据及其新鲜度状态(fresh/stale/miss)
+	Get(ctx context.Context, key string) ([]byte, CacheStatus)
+	// Set 写入缓存,ttl 为 0 时使用后端默认 TTL,内部据此派生 SoftExpiry/HardExpiry
+	Set(ctx context.Context, key string, data []byte, ttl time.Duration)
+	// Delete 删除指定 key 的缓存
+	Delete(ctx context.Context, key string)
+	// ShouldRefresh 判断某个处于 stale 窗口的 key 访问是否足够热门,
+	// 值得触发一次后台刷新,而不是让冷 key 白白消耗一次回源
+	ShouldRefresh(ctx context.Context, key string) bool
+}
+
+// jitterTTL 在基准 TTL 上叠加 ±TTL_JITTER 的随机抖动,
+// 避免批量加载的缓存条目在同一时刻集中过期导致回源风暴
+func jitterTTL(base time.Duration) time.Duration {
+	jitter := time.Duration(rand.Int63n(int64(2*TTL_JITTER))) - TTL_JITTER
+	ttl := base + jitter
+	if ttl < 0 {
+		ttl = base
+	}
+	return ttl
+}
+
+// defaultIsAllow 实现请求级别的缓存许可规则:配置文件中的 no-cache 规则优先级最高,
+// 其次只缓存 200/404 的白名单路径,账号相关的鉴权敏感路径一律跳过;401/403/429/5xx 永不缓存
+func defaultIsAllow(req *http.Request, statusCode int, body []byte) bool {
+	path := req.URL.Path
+	if isNoCacheRule(path) {
+		return false
+	}
+
+	if !isCacheableStatus(statusCode) {
+		return false
+	}
+
+	for _, prefix := range uncacheablePathPrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return false
+		}
+	}
+
+	for _, prefix := range cacheablePathPrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"container/list"
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// STALE_HARD_MULTIPLIER 决定 HardExpiry 相对 SoftExpiry 窗口的倍数,
+// 例如 ttl=10min 时 soft=10min、stale 窗口再持续 5 倍(50min),hard 共计 60min
+const STALE_HARD_MULTIPLIER = 5
+
+// HOT_KEY_REFRESH_THRESHOLD 是触发后台刷新所需的单个清理窗口内命中次数,
+// 低于该值的冷 key 在 stale 窗口内只会被直接返回,不会触发回源
+const HOT_KEY_REFRESH_THRESHOLD = 5
+
+// 缓存条目结构,同时承载 stale-while-revalidate 所需的 soft/hard 过期时间
+type CacheEntry struct {
+	Key        string
+	Data       []byte
+	SoftExpiry time.Time
+	HardExpiry time.Time
+	HitCount   int64 // 当前清理窗口内的命中次数,原子操作
+}
+
+// MemoryCache 是基于进程内 container/list 实现的 LRU 缓存,
+// 淘汰和访问都是 O(1),真正的热度(最近访问)而非插入顺序驱动淘汰
+type MemoryCache struct {
+	mu       sync.Mutex
+	elements map[string]*list.Element
+	order    *list.List // 链表头部是最近访问的元素,尾部是最久未访问的
+	enabled  bool
+	ttl      time.Duration
+	maxSize  int
+}
+
+// NewMemoryCache 创建新的内存 LRU 缓存管理器
+func NewMemoryCache(enabled bool, ttl time.Duration) *MemoryCache {
+	return NewMemoryCacheWithSize(enabled, ttl, MAX_CACHE_SIZE)
+}
+
+// NewMemoryCacheWithSize 创建指定最大容量的内存 LRU 缓存管理器
+func NewMemoryCacheWithSize(enabled bool, ttl time.Duration, maxSize int) *MemoryCache {
+	cm := &MemoryCache{
+		elements: make(map[string]*list.Element),
+		order:    list.New(),
+		enabled:  enabled,
+		ttl:      ttl,
+		maxSize:  maxSize,
+	}
+	// 启动定期清理协程,兜底清理已过期条目并重置命中计数窗口
+	go cm.startCleanup()
+	return cm
+}
+
+func (cm *MemoryCache) Enable() bool {
+	return cm.enabled
+}
+
+func (cm *MemoryCache) TTL() time.Duration {
+	return cm.ttl
+}
+
+func (cm *MemoryCache) IsAllow(req *http.Request, statusCode int, body []byte) bool {
+	return defaultIsAllow(req, statusCode, body)
+}
+
+// Get 获取缓存,命中时将条目移动到链表头部并累加命中计数;
+// 根据 soft/hard expiry 返回 fresh、stale 或 miss
+func (cm *MemoryCache) Get(ctx context.Context, key string) ([]byte, CacheStatus) {
+	cm.mu.Lock()
+	elem, exists := cm.elements[key]
+	if !exists {
+		cm.mu.Unlock()
+		return nil, CacheMiss
+	}
+
+	entry := elem.Value.(*CacheEntry)
+	now := time.Now()
+	if now.After(entry.HardExpiry) {
+		cm.removeElement(elem)
+		cm.mu.Unlock()
+		return nil, CacheMiss
+	}
+
+	cm.order.MoveToFront(elem)
+	data := entry.Data
+	status := CacheFresh
+	if now.After(entry.SoftExpiry) {
+		status = CacheStale
+	}
+	cm.mu.Unlock()
+
+	atomic.AddInt64(&entry.HitCount, 1)
+	return data, status
+}
+
+// Set 设置缓存,超出容量时淘汰链表尾部(最久未访问)的条目。
+// SoftExpiry 为 ttl 加抖动,HardExpiry 在此基础上再延长 STALE_HARD_MULTIPLIER 倍
+func (cm *MemoryCache) Set(ctx context.Context, key string, data []byte, ttl time.Duration) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	if ttl <= 0 {
+		ttl = cm.ttl
+	}
+	now := time.Now()
+	softTTL := jitterTTL(ttl)
+	softExpiry := now.Add(softTTL)
+	hardExpiry := softExpiry.Add(STALE_HARD_MULTIPLIER * ttl)
+
+	if elem, exists := cm.elements[key]; exists {
+		entry := elem.Value.(*CacheEntry)
+		entry.Data = data
+		entry.SoftExpiry = softExpiry
+		entry.HardExpiry = hardExpiry
+		cm.order.MoveToFront(elem)
+		return
+	}
+
+	entry := &CacheEntry{Key: key, Data: data, SoftExpiry: softExpiry, HardExpiry: hardExpiry}
+	elem := cm.order.PushFront(entry)
+	cm.elements[key] = elem
+
+	if cm.order.Len() > cm.maxSize {
+		cm.evictOldest()
+	}
+}
+
+// Delete 删除指定 key 的缓存
+func (cm *MemoryCache) Delete(ctx context.Context, key string) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	if elem, exists := cm.elements[key]; exists {
+		cm.removeElement(elem)
+	}
+}
+
+// Len 返回当前缓存的条目数,供 /metrics 上报 tmdb_proxy_cache_entries
+func (cm *MemoryCache) Len() int {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	return cm.order.Len()
+}
+
+// ApproxBytes 返回当前缓存条目数据的近似总字节数,供 /metrics 上报 tmdb_proxy_cache_bytes
+func (cm *MemoryCache) ApproxBytes() int64 {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	var total int64
+	for elem := cm.order.Front(); elem != nil; elem = elem.Next() {
+		total += int64(len(elem.Value.(*CacheEntry).Data))
+	}
+	return total
+}
+
+// ShouldRefresh 判断某个 key 在当前窗口内是否足够热门,值得后台刷新
+func (cm *MemoryCache) ShouldRefresh(ctx context.Context, key string) bool {
+	cm.mu.Lock()
+	elem, exists := cm.elements[key]
+	cm.mu.Unlock()
+	if !exists {
+		return false
+	}
+
+	entry := elem.Value.(*CacheEntry)
+	return atomic.LoadInt64(&entry.HitCount) >= HOT_KEY_REFRESH_THRESHOLD
+}
+
+// evictOldest 淘汰链表尾部的最久未访问条目,调用方需持有 cm.mu
+func (cm *MemoryCache) evictOldest() {
+	elem := cm.order.Back()
+	if elem != nil {
+		cm.removeElement(elem)
+	}
+}
+
+// removeElement 从链表和索引 map 中移除元素,调用方需持有 cm.mu
+func (cm *MemoryCache) removeElement(elem *list.Element) {
+	entry := elem.Value.(*CacheEntry)
+	cm.order.Remove(elem)
+	delete(cm.elements, entry.Key)
+}
+
+// cleanExpiredCache 清理已过期(超过 HardExpiry)的缓存条目,
+// 并重置尚存活条目的命中计数,开启下一个统计窗口
+func (cm *MemoryCache) cleanExpiredCache() {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+
+	now := time.Now()
+	var next *list.Element
+	for elem := cm.order.Back(); elem != nil; elem = next {
+		next = elem.Prev()
+		entry := elem.Value.(*CacheEntry)
+		if now.After(entry.HardExpiry) {
+			cm.removeElement(elem)
+		} else {
+			atomic.StoreInt64(&entry.HitCount, 0)
+		}
+	}
+}
+
+// 启动定期清理
+func (cm *MemoryCache) startCleanup() {
+	ticker := time.NewTicker(CLEANUP_INTERVAL)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		cm.cleanExpiredCache()
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryCacheLRUEviction(t *testing.T) {
+	cache := NewMemoryCacheWithSize(true, time.Minute, 2)
+	ctx := context.Background()
+
+	cache.Set(ctx, "a", []byte("1"), time.Minute)
+	cache.Set(ctx, "b", []byte("2"), time.Minute)
+
+	// 访问 a,使其成为最近使用,b 退居最久未访问
+	if _, status := cache.Get(ctx, "a"); status != CacheFresh {
+		t.Fatalf("expected a to be present, got %v", status)
+	}
+
+	// 插入 c 会超出容量上限,应当淘汰最久未访问的 b,而不是最先插入的 a
+	cache.Set(ctx, "c", []byte("3"), time.Minute)
+
+	if _, status := cache.Get(ctx, "b"); status != CacheMiss {
+		t.Fatal("expected b to be evicted as least recently used")
+	}
+	if _, status := cache.Get(ctx, "a"); status != CacheFresh {
+		t.Fatalf("expected a to survive eviction due to recent access, got %v", status)
+	}
+	if _, status := cache.Get(ctx, "c"); status != CacheFresh {
+		t.Fatalf("expected c to be present, got %v", status)
+	}
+}
+
+func TestMemoryCacheStaleWhileRevalidate(t *testing.T) {
+	cache := NewMemoryCacheWithSize(true, time.Minute, 10)
+	ctx := context.Background()
+
+	cache.Set(ctx, "hot", []byte("v1"), time.Minute)
+
+	// 直接回拨 SoftExpiry 到过去,模拟进入 stale 窗口,
+	// 避免在测试中真实等待 TTL_JITTER 量级的时间
+	elem := cache.elements["hot"]
+	entry := elem.Value.(*CacheEntry)
+	entry.SoftExpiry = time.Now().Add(-time.Second)
+
+	data, status := cache.Get(ctx, "hot")
+	if status != CacheStale {
+		t.Fatalf("expected CacheStale once past soft expiry, got %v", status)
+	}
+	if string(data) != "v1" {
+		t.Fatalf("expected stale data to still be served, got %s", data)
+	}
+
+	if cache.ShouldRefresh(ctx, "hot") {
+		t.Fatal("expected ShouldRefresh to be false below hit threshold")
+	}
+	for i := int64(0); i < HOT_KEY_REFRESH_THRESHOLD; i++ {
+		cache.Get(ctx, "hot")
+	}
+	if !cache.ShouldRefresh(ctx, "hot") {
+		t.Fatal("expected ShouldRefresh to be true once hit threshold is reached")
+	}
+}
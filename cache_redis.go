@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisEnvelope 是写入 Redis 的值的结构,携带 stale-while-revalidate
+// 所需的 soft/hard 过期时间,Redis 自身的 TTL 设为 HardExpiry
+type redisEnvelope struct {
+	Data       []byte    `json:"data"`
+	SoftExpiry time.Time `json:"soft_expiry"`
+}
+
+// RedisCache 是基于 Redis 的 ICache 实现,适合多实例部署共享缓存
+type RedisCache struct {
+	client  *redis.Client
+	enabled bool
+	ttl     time.Duration
+}
+
+// NewRedisCache 创建新的 Redis 缓存管理器
+func NewRedisCache(addr, password string, db int, enabled bool, ttl time.Duration) *RedisCache {
+	client := redis.NewClient(&redis.Options{
+		Addr:     addr,
+		Password: password,
+		DB:       db,
+	})
+
+	return &RedisCache{
+		client:  client,
+		enabled: enabled,
+		ttl:     ttl,
+	}
+}
+
+func (rc *RedisCache) Enable() bool {
+	return rc.enabled
+}
+
+func (rc *RedisCache) TTL() time.Duration {
+	return rc.ttl
+}
+
+func (rc *RedisCache) IsAllow(req *http.Request, statusCode int, body []byte) bool {
+	return defaultIsAllow(req, statusCode, body)
+}
+
+// Get 从 Redis 读取缓存,Redis 原生 TTL 等价于 HardExpiry,
+// 所以键存在即未过 hard 窗口,再比较 SoftExpiry 判断是否 stale
+func (rc *RedisCache) Get(ctx context.Context, key string) ([]byte, CacheStatus) {
+	raw, err := rc.client.Get(ctx, key).Bytes()
+	if err != nil {
+		if err != redis.Nil {
+			log.Printf("Redis get error: %v", err)
+		}
+		return nil, CacheMiss
+	}
+
+	var envelope redisEnvelope
+	if err := json.Unmarshal(raw, &envelope); err != nil {
+		log.Printf("Redis envelope decode error: %v", err)
+		return nil, CacheMiss
+	}
+
+	rc.client.Incr(ctx, hitCounterKey(key))
+
+	if time.Now().After(envelope.SoftExpiry) {
+		return envelope.Data, CacheStale
+	}
+	return envelope.Data, CacheFresh
+}
+
+// Set 写入 Redis 缓存,ttl 为 0 时使用默认 TTL
+func (rc *RedisCache) Set(ctx context.Context, key string, data []byte, ttl time.Duration) {
+	if ttl <= 0 {
+		ttl = rc.ttl
+	}
+
+	softTTL := jitterTTL(ttl)
+	hardTTL := softTTL + STALE_HARD_MULTIPLIER*ttl
+
+	envelope := redisEnvelope{Data: data, SoftExpiry: time.Now().Add(softTTL)}
+	raw, err := json.Marshal(envelope)
+	if err != nil {
+		log.Printf("Redis envelope encode error: %v", err)
+		return
+	}
+
+	if err := rc.client.Set(ctx, key, raw, hardTTL).Err(); err != nil {
+		log.Printf("Redis set error: %v", err)
+	}
+	rc.client.Set(ctx, hitCounterKey(key), 0, hardTTL)
+}
+
+// Delete 删除 Redis 中指定 key 的缓存
+func (rc *RedisCache) Delete(ctx context.Context, key string) {
+	if err := rc.client.Del(ctx, key, hitCounterKey(key)).Err(); err != nil {
+		log.Printf("Redis delete error: %v", err)
+	}
+}
+
+// ShouldRefresh 判断某个 key 在当前窗口内是否足够热门,值得后台刷新
+func (rc *RedisCache) ShouldRefresh(ctx context.Context, key string) bool {
+	count, err := rc.client.Get(ctx, hitCounterKey(key)).Int64()
+	if err != nil {
+		return false
+	}
+	return count >= HOT_KEY_REFRESH_THRESHOLD
+}
+
+// hitCounterKey 派生出该 key 对应的命中计数器键名
+func hitCounterKey(key string) string {
+	return "hits:" + key
+}
+
+// Len 返回当前 Redis 数据库的 key 数量,供 /metrics 上报 tmdb_proxy_cache_entries
+func (rc *RedisCache) Len() int {
+	ctx := context.Background()
+	size, err := rc.client.DBSize(ctx).Result()
+	if err != nil {
+		log.Printf("Redis dbsize error: %v", err)
+		return 0
+	}
+	return int(size)
+}
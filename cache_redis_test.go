@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+)
+
+// newTestRedisCache 在 REDIS_ADDR 指向的实例上建立一个用完即丢的 RedisCache,
+// 没有配置 REDIS_ADDR 时跳过,避免把真实 Redis 变成 CI 的硬依赖
+func newTestRedisCache(t *testing.T) *RedisCache {
+	t.Helper()
+	addr := os.Getenv("REDIS_ADDR")
+	if addr == "" {
+		t.Skip("REDIS_ADDR not set, skipping RedisCache test")
+	}
+
+	cache := NewRedisCache(addr, os.Getenv("REDIS_PASSWORD"), 0, true, CACHE_DURATION)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := cache.client.Ping(ctx).Err(); err != nil {
+		t.Skipf("REDIS_ADDR %s unreachable: %v", addr, err)
+	}
+
+	t.Cleanup(func() {
+		cache.client.FlushDB(context.Background())
+		cache.client.Close()
+	})
+	return cache
+}
+
+func TestRedisCache(t *testing.T) {
+	cache := newTestRedisCache(t)
+	testICacheImplementation(t, cache)
+}
+
+func TestRedisCacheShouldRefresh(t *testing.T) {
+	cache := newTestRedisCache(t)
+	ctx := context.Background()
+
+	cache.Set(ctx, "hot", []byte("v1"), time.Minute)
+
+	if cache.ShouldRefresh(ctx, "hot") {
+		t.Fatal("expected ShouldRefresh to be false below hit threshold")
+	}
+	for i := int64(0); i < HOT_KEY_REFRESH_THRESHOLD; i++ {
+		cache.Get(ctx, "hot")
+	}
+	if !cache.ShouldRefresh(ctx, "hot") {
+		t.Fatal("expected ShouldRefresh to be true once hit threshold is reached")
+	}
+}
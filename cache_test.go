@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// testICacheImplementation 是所有 ICache 实现都必须通过的共享测试套件
+func testICacheImplementation(t *testing.T, cache ICache) {
+	t.Helper()
+	ctx := context.Background()
+
+	t.Run("SetAndGet", func(t *testing.T) {
+		cache.Set(ctx, "key1", []byte("value1"), time.Minute)
+		data, status := cache.Get(ctx, "key1")
+		if status != CacheFresh {
+			t.Fatalf("expected CacheFresh after Set, got %v", status)
+		}
+		if string(data) != "value1" {
+			t.Fatalf("expected value1, got %s", data)
+		}
+	})
+
+	t.Run("MissingKey", func(t *testing.T) {
+		if _, status := cache.Get(ctx, "does-not-exist"); status != CacheMiss {
+			t.Fatalf("expected CacheMiss for unknown key, got %v", status)
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		cache.Set(ctx, "key2", []byte("value2"), time.Minute)
+		cache.Delete(ctx, "key2")
+		if _, status := cache.Get(ctx, "key2"); status != CacheMiss {
+			t.Fatalf("expected CacheMiss after Delete, got %v", status)
+		}
+	})
+
+	t.Run("IsAllow", func(t *testing.T) {
+		allowed := httptest.NewRequest(http.MethodGet, "/3/movie/550", nil)
+		if !cache.IsAllow(allowed, http.StatusOK, nil) {
+			t.Fatal("expected /3/movie/ to be cacheable")
+		}
+
+		denied := httptest.NewRequest(http.MethodGet, "/3/account/1234", nil)
+		if cache.IsAllow(denied, http.StatusOK, nil) {
+			t.Fatal("expected /3/account/ to never be cacheable")
+		}
+
+		nonOK := httptest.NewRequest(http.MethodGet, "/3/movie/550", nil)
+		if cache.IsAllow(nonOK, http.StatusInternalServerError, nil) {
+			t.Fatal("expected non-200 responses to not be cacheable")
+		}
+	})
+}
+
+func TestMemoryCache(t *testing.T) {
+	cache := NewMemoryCache(true, CACHE_DURATION)
+	testICacheImplementation(t, cache)
+}
+
+func TestJitterTTL(t *testing.T) {
+	base := 10 * time.Minute
+	for i := 0; i < 100; i++ {
+		ttl := jitterTTL(base)
+		if ttl < base-TTL_JITTER || ttl > base+TTL_JITTER {
+			t.Fatalf("jittered ttl %s out of bounds [%s, %s]", ttl, base-TTL_JITTER, base+TTL_JITTER)
+		}
+	}
+}
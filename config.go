@@ -0,0 +1,199 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/time/rate"
+	"gopkg.in/yaml.v3"
+)
+
+// Config 是 -config 指向的配置文件结构,各字段存在时会覆盖对应的命令行参数
+type Config struct {
+	Server struct {
+		Port        string `yaml:"port" json:"port"`
+		TLSCertFile string `yaml:"tls_cert_file" json:"tls_cert_file"`
+		TLSKeyFile  string `yaml:"tls_key_file" json:"tls_key_file"`
+	} `yaml:"server" json:"server"`
+
+	Cache struct {
+		Backend       string   `yaml:"backend" json:"backend"`
+		RedisAddr     string   `yaml:"redis_addr" json:"redis_addr"`
+		RedisPassword string   `yaml:"redis_password" json:"redis_password"`
+		RedisDB       int      `yaml:"redis_db" json:"redis_db"`
+		MaxSize       int      `yaml:"max_size" json:"max_size"`
+		DefaultTTL    Duration `yaml:"default_ttl" json:"default_ttl"`
+		Jitter        Duration `yaml:"jitter" json:"jitter"`
+	} `yaml:"cache" json:"cache"`
+
+	Upstream struct {
+		BaseURL   string   `yaml:"base_url" json:"base_url"`
+		Timeout   Duration `yaml:"timeout" json:"timeout"`
+		Retries   int      `yaml:"retries" json:"retries"`
+		RateLimit float64  `yaml:"rate_limit" json:"rate_limit"`
+	} `yaml:"upstream" json:"upstream"`
+
+	Rules []RuleConfig `yaml:"rules" json:"rules"`
+}
+
+// RuleConfig 把一个路径 glob 模式映射到对应的缓存 TTL 或 no-cache 标记,
+// 例如 /3/configuration -> 24h,/3/account/* -> no-cache
+type RuleConfig struct {
+	Pattern string   `yaml:"pattern" json:"pattern"`
+	TTL     Duration `yaml:"ttl" json:"ttl"`
+	NoCache bool     `yaml:"no_cache" json:"no_cache"`
+}
+
+// Duration 包装 time.Duration,使配置文件里的 "15m"、"24h" 这类字符串
+// 在 YAML 和 JSON 两种格式下都能解析,而不必像原生 encoding/json 那样要求纳秒数
+type Duration time.Duration
+
+// UnmarshalJSON 支持把 "15m"、"24h" 这样的字符串解析为 Duration
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// UnmarshalYAML 委托给 time.Duration 自身的 YAML 解析逻辑
+func (d *Duration) UnmarshalYAML(value *yaml.Node) error {
+	var td time.Duration
+	if err := value.Decode(&td); err != nil {
+		return err
+	}
+	*d = Duration(td)
+	return nil
+}
+
+// serverSettings 汇总一次启动实际生效的配置,由命令行参数先行填充,
+// 再按需被 -config 指定的配置文件覆盖
+type serverSettings struct {
+	port          string
+	cacheBackend  string
+	redisAddr     string
+	redisPassword string
+	redisDB       int
+	tlsCertFile   string
+	tlsKeyFile    string
+}
+
+// LoadConfig 读取并解析配置文件,根据扩展名选择 YAML 或 JSON 解码器
+func LoadConfig(configPath string) (*Config, error) {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("read config: %w", err)
+	}
+
+	cfg := &Config{}
+	switch strings.ToLower(path.Ext(configPath)) {
+	case ".json":
+		if err := json.Unmarshal(data, cfg); err != nil {
+			return nil, fmt.Errorf("parse json config: %w", err)
+		}
+	default:
+		if err := yaml.Unmarshal(data, cfg); err != nil {
+			return nil, fmt.Errorf("parse yaml config: %w", err)
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Validate 检查配置的合法性,目前主要校验每条规则的 glob 是否是合法模式。
+// 用 path.Match 做语法校验(它能识别非法的字符类等写法),实际匹配时
+// 由 compileRuleGlob 接管,以便让 "*" 跨路径段匹配
+func (c *Config) Validate() error {
+	for _, rule := range c.Rules {
+		if rule.Pattern == "" {
+			return fmt.Errorf("rule has empty pattern")
+		}
+		if _, err := path.Match(rule.Pattern, "/3/probe"); err != nil {
+			return fmt.Errorf("rule %q has invalid glob pattern: %w", rule.Pattern, err)
+		}
+	}
+	return nil
+}
+
+// applyConfig 把配置文件中出现的字段覆盖到 settings 和相关全局变量上,
+// 未出现的字段保留命令行参数(或其默认值)原有的效果;最后装载规则集
+func applyConfig(cfg *Config, settings *serverSettings) {
+	if cfg.Server.Port != "" {
+		settings.port = cfg.Server.Port
+	}
+	settings.tlsCertFile = cfg.Server.TLSCertFile
+	settings.tlsKeyFile = cfg.Server.TLSKeyFile
+
+	if cfg.Cache.Backend != "" {
+		settings.cacheBackend = cfg.Cache.Backend
+	}
+	if cfg.Cache.RedisAddr != "" {
+		settings.redisAddr = cfg.Cache.RedisAddr
+	}
+	if cfg.Cache.RedisPassword != "" {
+		settings.redisPassword = cfg.Cache.RedisPassword
+	}
+	if cfg.Cache.RedisDB != 0 {
+		settings.redisDB = cfg.Cache.RedisDB
+	}
+	if cfg.Cache.MaxSize > 0 {
+		MAX_CACHE_SIZE = cfg.Cache.MaxSize
+	}
+	if cfg.Cache.DefaultTTL > 0 {
+		CACHE_DURATION = time.Duration(cfg.Cache.DefaultTTL)
+	}
+	if cfg.Cache.Jitter > 0 {
+		TTL_JITTER = time.Duration(cfg.Cache.Jitter)
+	}
+
+	if cfg.Upstream.BaseURL != "" {
+		TMDB_BASE_URL = cfg.Upstream.BaseURL
+	}
+	if cfg.Upstream.Timeout > 0 {
+		UPSTREAM_TIMEOUT = time.Duration(cfg.Upstream.Timeout)
+	}
+	if cfg.Upstream.Retries > 0 {
+		MAX_RETRIES = cfg.Upstream.Retries
+	}
+	if cfg.Upstream.RateLimit > 0 {
+		RATE_LIMIT = cfg.Upstream.RateLimit
+	}
+
+	setRules(cfg.Rules)
+}
+
+// watchConfigReload 监听 SIGHUP,收到信号后重新加载 configPath 并原子替换规则集,
+// 不会重建 cacheManager,因此内存缓存在重载过程中不会丢失
+func watchConfigReload(configPath string) {
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+	for range sighup {
+		cfg, err := LoadConfig(configPath)
+		if err != nil {
+			log.Printf("config reload failed, keeping previous rules: %v", err)
+			continue
+		}
+		setRules(cfg.Rules)
+		if cfg.Upstream.RateLimit > 0 {
+			RATE_LIMIT = cfg.Upstream.RateLimit
+			setUpstreamLimiter(rate.NewLimiter(rate.Limit(RATE_LIMIT), int(RATE_LIMIT)))
+		}
+		log.Printf("config reloaded from %s (%d rules)", configPath, len(cfg.Rules))
+	}
+}
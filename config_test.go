@@ -0,0 +1,100 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadConfigYAML(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.yaml")
+	content := `
+server:
+  port: "9090"
+cache:
+  backend: redis
+  default_ttl: 5m
+rules:
+  - pattern: "/3/configuration"
+    ttl: 24h
+  - pattern: "/3/account/*"
+    no_cache: true
+`
+	if err := os.WriteFile(configPath, []byte(content), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	cfg, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if cfg.Server.Port != "9090" {
+		t.Errorf("Server.Port = %q, want 9090", cfg.Server.Port)
+	}
+	if cfg.Cache.Backend != "redis" {
+		t.Errorf("Cache.Backend = %q, want redis", cfg.Cache.Backend)
+	}
+	if cfg.Cache.DefaultTTL != Duration(5*time.Minute) {
+		t.Errorf("Cache.DefaultTTL = %v, want 5m", cfg.Cache.DefaultTTL)
+	}
+	if len(cfg.Rules) != 2 {
+		t.Fatalf("len(Rules) = %d, want 2", len(cfg.Rules))
+	}
+	if cfg.Rules[0].TTL != Duration(24*time.Hour) {
+		t.Errorf("Rules[0].TTL = %v, want 24h", cfg.Rules[0].TTL)
+	}
+	if !cfg.Rules[1].NoCache {
+		t.Errorf("Rules[1].NoCache = false, want true")
+	}
+}
+
+func TestLoadConfigJSON(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.json")
+	content := `{"server": {"port": "9091"}, "rules": [{"pattern": "/3/trending/*", "ttl": "15m"}]}`
+	if err := os.WriteFile(configPath, []byte(content), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	cfg, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if cfg.Server.Port != "9091" {
+		t.Errorf("Server.Port = %q, want 9091", cfg.Server.Port)
+	}
+	if len(cfg.Rules) != 1 || cfg.Rules[0].TTL != Duration(15*time.Minute) {
+		t.Errorf("unexpected rules: %+v", cfg.Rules)
+	}
+}
+
+func TestLoadConfigMissingFile(t *testing.T) {
+	if _, err := LoadConfig("/nonexistent/config.yaml"); err == nil {
+		t.Error("LoadConfig() on missing file: error = nil, want error")
+	}
+}
+
+func TestLoadConfigInvalidGlob(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.yaml")
+	content := `
+rules:
+  - pattern: "[unclosed"
+`
+	if err := os.WriteFile(configPath, []byte(content), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	if _, err := LoadConfig(configPath); err == nil {
+		t.Error("LoadConfig() with invalid glob: error = nil, want error")
+	}
+}
+
+func TestConfigValidateEmptyPattern(t *testing.T) {
+	cfg := &Config{Rules: []RuleConfig{{Pattern: ""}}}
+	if err := cfg.Validate(); err == nil {
+		t.Error("Validate() with empty pattern: error = nil, want error")
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// READYZ_CACHE_TTL 是 /readyz 探测 TMDB 的结果缓存时长,避免每次健康检查都打到上游
+const READYZ_CACHE_TTL = 5 * time.Minute
+
+var readyState struct {
+	mu      sync.Mutex
+	healthy bool
+	checked time.Time
+	lastErr error
+}
+
+// healthzHandler 是存活探针,只要进程在跑就返回 200,不依赖任何外部服务
+func healthzHandler(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("ok"))
+}
+
+// readyzHandler 是就绪探针,探测 TMDB /3/configuration 是否可达,
+// 结果缓存 READYZ_CACHE_TTL,避免探针本身给 TMDB 增加压力
+func readyzHandler(w http.ResponseWriter, r *http.Request) {
+	readyState.mu.Lock()
+	stale := time.Since(readyState.checked) > READYZ_CACHE_TTL
+	healthy := readyState.healthy
+	lastErr := readyState.lastErr
+	readyState.mu.Unlock()
+
+	if stale {
+		healthy, lastErr = checkTMDBReachable(r.Context())
+		readyState.mu.Lock()
+		readyState.healthy = healthy
+		readyState.lastErr = lastErr
+		readyState.checked = time.Now()
+		readyState.mu.Unlock()
+	}
+
+	if !healthy {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte("not ready: " + lastErr.Error()))
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("ready"))
+}
+
+// checkTMDBReachable 对 TMDB /3/configuration 做一次轻量请求,用来判断上游是否可达
+func checkTMDBReachable(ctx context.Context) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, TMDB_BASE_URL+"/3/configuration", nil)
+	if err != nil {
+		return false, err
+	}
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return false, fmt.Errorf("tmdb returned status %d", resp.StatusCode)
+	}
+	return true, nil
+}
@@ -0,0 +1,16 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHealthzHandler(t *testing.T) {
+	rr := httptest.NewRecorder()
+	healthzHandler(rr, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func httptestRequestWithAuth(auth string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/3/movie/550", nil)
+	if auth != "" {
+		r.Header.Set("Authorization", auth)
+	}
+	return r
+}
+
+func TestCacheStatusLabel(t *testing.T) {
+	cases := []struct {
+		enabled bool
+		status  CacheStatus
+		want    string
+	}{
+		{true, CacheFresh, "hit"},
+		{true, CacheStale, "stale"},
+		{true, CacheMiss, "miss"},
+		{false, CacheFresh, "bypass"},
+	}
+
+	for _, c := range cases {
+		if got := cacheStatusLabel(c.enabled, c.status); got != c.want {
+			t.Fatalf("cacheStatusLabel(%v, %v) = %q, want %q", c.enabled, c.status, got, c.want)
+		}
+	}
+}
+
+func TestAuthFingerprintStability(t *testing.T) {
+	r1 := httptestRequestWithAuth("Bearer token-a")
+	r2 := httptestRequestWithAuth("Bearer token-a")
+	r3 := httptestRequestWithAuth("Bearer token-b")
+
+	if authFingerprint(r1) != authFingerprint(r2) {
+		t.Fatal("expected identical auth headers to fingerprint identically")
+	}
+	if authFingerprint(r1) == authFingerprint(r3) {
+		t.Fatal("expected different auth headers to fingerprint differently")
+	}
+	if authFingerprint(httptestRequestWithAuth("")) != "" {
+		t.Fatal("expected empty Authorization header to yield an empty fingerprint")
+	}
+}
@@ -1,138 +1,61 @@
 package main
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"flag"
-	"io"
 	"log"
 	"net/http"
-	"sync"
+	"strconv"
 	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"golang.org/x/sync/singleflight"
+	"golang.org/x/time/rate"
 )
 
 const (
-	TMDB_BASE_URL    = "https://api.themoviedb.org"
-	CACHE_DURATION   = 10 * time.Minute
-	MAX_CACHE_SIZE   = 1000
 	CLEANUP_INTERVAL = 10 * time.Minute
 )
 
-// 缓存条目结构
-type CacheEntry struct {
-	Data   []byte
-	Expiry time.Time
-}
-
-// 缓存管理器
-type CacheManager struct {
-	cache map[string]*CacheEntry
-	mu    sync.RWMutex
-}
-
-// 创建新的缓存管理器
-func NewCacheManager() *CacheManager {
-	cm := &CacheManager{
-		cache: make(map[string]*CacheEntry),
-	}
-	// 启动定期清理协程
-	go cm.startCleanup()
-	return cm
-}
-
-// 获取缓存
-func (cm *CacheManager) Get(key string) ([]byte, bool) {
-	cm.mu.RLock()
-	defer cm.mu.RUnlock()
-
-	entry, exists := cm.cache[key]
-	if !exists {
-		return nil, false
-	}
-
-	if time.Now().After(entry.Expiry) {
-		return nil, false
-	}
-
-	return entry.Data, true
-}
-
-// 设置缓存
-func (cm *CacheManager) Set(key string, data []byte) {
-	cm.mu.Lock()
-	defer cm.mu.Unlock()
-
-	// 检查缓存大小
-	cm.checkCacheSize()
-
-	cm.cache[key] = &CacheEntry{
-		Data:   data,
-		Expiry: time.Now().Add(CACHE_DURATION),
-	}
-}
-
-// 检查并清理超出大小限制的缓存
-func (cm *CacheManager) checkCacheSize() {
-	if len(cm.cache) <= MAX_CACHE_SIZE {
-		return
-	}
-
-	// 找出最旧的条目
-	type entry struct {
-		key    string
-		expiry time.Time
-	}
-	entries := make([]entry, 0, len(cm.cache))
-
-	for key, val := range cm.cache {
-		entries = append(entries, entry{key: key, expiry: val.Expiry})
-	}
-
-	// 按过期时间排序
-	for i := 0; i < len(entries)-1; i++ {
-		for j := i + 1; j < len(entries); j++ {
-			if entries[i].expiry.After(entries[j].expiry) {
-				entries[i], entries[j] = entries[j], entries[i]
-			}
-		}
-	}
-
-	// 删除最旧的条目
-	deleteCount := len(cm.cache) - MAX_CACHE_SIZE
-	for i := 0; i < deleteCount; i++ {
-		delete(cm.cache, entries[i].key)
-	}
-
-	log.Printf("Cleaned %d old cache entries", deleteCount)
-}
+// TMDB_BASE_URL、CACHE_DURATION 和 MAX_CACHE_SIZE 可通过命令行参数或
+// -config 指定的配置文件(优先级更高)覆盖,此处保留默认值
+var (
+	TMDB_BASE_URL  = "https://api.themoviedb.org"
+	CACHE_DURATION = 10 * time.Minute
+	MAX_CACHE_SIZE = 1000
+)
 
-// 清理过期缓存
-func (cm *CacheManager) cleanExpiredCache() {
-	cm.mu.Lock()
-	defer cm.mu.Unlock()
+// 全局缓存管理器,具体实现由 -cache-backend 决定
+var cacheManager ICache
 
-	now := time.Now()
-	for key, entry := range cm.cache {
-		if now.After(entry.Expiry) {
-			delete(cm.cache, key)
-		}
-	}
-}
+// 用于合并并发请求,避免同一 TMDB 路径的缓存击穿造成多次回源
+var upstreamGroup singleflight.Group
 
-// 启动定期清理
-func (cm *CacheManager) startCleanup() {
-	ticker := time.NewTicker(CLEANUP_INTERVAL)
-	defer ticker.Stop()
+// 用于合并 stale-while-revalidate 的后台刷新,
+// 确保同一个 stale key 的并发命中只触发一次后台回源
+var refreshGroup singleflight.Group
 
-	for range ticker.C {
-		cm.cleanExpiredCache()
+// newCacheManager 根据命令行参数构建对应的 ICache 实现
+func newCacheManager(backend string, redisAddr, redisPassword string, redisDB int) ICache {
+	switch backend {
+	case "redis":
+		return NewRedisCache(redisAddr, redisPassword, redisDB, true, CACHE_DURATION)
+	case "none":
+		return NewMemoryCache(false, CACHE_DURATION)
+	default:
+		return NewMemoryCacheWithSize(true, CACHE_DURATION, MAX_CACHE_SIZE)
 	}
 }
 
-// 全局缓存管理器
-var cacheManager = NewCacheManager()
-
 // 处理请求的主函数
 func handler(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	requestID := newRequestID()
+	w.Header().Set("X-Request-ID", requestID)
+
 	// 设置 CORS 头
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
@@ -144,67 +67,121 @@ func handler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	ctx := r.Context()
+
 	// 获取完整路径
 	fullPath := r.URL.RequestURI()
 	cacheKey := fullPath
 
-	// 检查缓存
-	if cachedData, found := cacheManager.Get(cacheKey); found {
-		log.Printf("Cache hit: %s", fullPath)
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		w.Write(cachedData)
-		return
+	// 检查缓存:fresh 直接返回;stale 也立即返回陈旧数据,
+	// 但如果该 key 足够热门,顺带触发一次去重后的后台刷新
+	if cacheManager.Enable() {
+		cachedData, status := cacheManager.Get(ctx, cacheKey)
+		if status == CacheFresh || status == CacheStale {
+			if status == CacheStale && r.Method == http.MethodGet && cacheManager.ShouldRefresh(ctx, cacheKey) {
+				triggerBackgroundRefresh(r, cacheKey, fullPath)
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			w.Write(cachedData)
+
+			logAccess(accessLogEntry{
+				requestID: requestID, method: r.Method, path: fullPath, clientIP: r.RemoteAddr,
+				cacheStatus: cacheStatusLabel(true, status), upstreamStatus: http.StatusOK,
+				totalDuration: time.Since(start).Seconds(), bytes: len(cachedData),
+				authFingerprint: authFingerprint(r),
+			})
+			requestsTotal.WithLabelValues("200", cacheStatusLabel(true, status)).Inc()
+			return
+		}
 	}
 
 	// 构建 TMDB 请求 URL
 	tmdbURL := TMDB_BASE_URL + fullPath
 
-	// 创建新请求
-	req, err := http.NewRequest(r.Method, tmdbURL, r.Body)
-	if err != nil {
-		log.Printf("Error creating request: %v", err)
-		sendErrorResponse(w, http.StatusInternalServerError, err.Error())
-		return
-	}
-
-	// 复制 Authorization header
-	if authHeader := r.Header.Get("Authorization"); authHeader != "" {
-		req.Header.Set("Authorization", authHeader)
-	}
-
-	// 发送请求
-	client := &http.Client{
-		Timeout: 30 * time.Second,
-	}
-	resp, err := client.Do(req)
+	// 以请求 URI + Authorization 指纹为 key 做 singleflight 合并,
+	// 避免同一时刻大量客户端 miss 同一个 TMDB 路径时重复回源
+	sfKey := singleflightKey(r)
+	upstreamStart := time.Now()
+	executed := false
+	result, err, shared := upstreamGroup.Do(sfKey, func() (interface{}, error) {
+		executed = true
+		return fetchUpstreamWithRetry(ctx, r, tmdbURL)
+	})
+	upstreamElapsed := time.Since(upstreamStart)
+	upstreamDuration.Observe(upstreamElapsed.Seconds())
+	setRateLimitHeaders(w)
 	if err != nil {
-		log.Printf("TMDB API error: %v", err)
 		sendErrorResponse(w, http.StatusBadGateway, err.Error())
+		logAccess(accessLogEntry{
+			requestID: requestID, method: r.Method, path: fullPath, clientIP: r.RemoteAddr,
+			cacheStatus: cacheStatusLabel(cacheManager.Enable(), CacheMiss), upstreamStatus: http.StatusBadGateway,
+			upstreamDuration: upstreamElapsed.Seconds(), totalDuration: time.Since(start).Seconds(),
+			authFingerprint: authFingerprint(r),
+		})
+		requestsTotal.WithLabelValues("502", cacheStatusLabel(cacheManager.Enable(), CacheMiss)).Inc()
 		return
 	}
-	defer resp.Body.Close()
-
-	// 读取响应体
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		log.Printf("Error reading response: %v", err)
-		sendErrorResponse(w, http.StatusInternalServerError, err.Error())
-		return
+	// shared 对组内所有调用者(包括真正执行了 fn 的那个)都是 true,
+	// 只有未执行 fn、搭了别人便车的 follower 才算一次"去重"
+	if shared && !executed {
+		singleflightDedupTotal.Inc()
 	}
+	upstream := result.(*upstreamResult)
 
-	// 只有响应状态码为 200 时才缓存
-	if resp.StatusCode == http.StatusOK {
-		cacheManager.Set(cacheKey, body)
-		log.Printf("Cache miss and stored: %s", fullPath)
-	} else {
-		log.Printf("Response not cached due to non-200 status: %d", resp.StatusCode)
+	// 由缓存后端的 IsAllow 决定该响应是否允许缓存,
+	// 而不是简单地按状态码一刀切;404 作为负缓存只短期保留
+	if cacheManager.Enable() && cacheManager.IsAllow(r, upstream.statusCode, upstream.body) {
+		ttl := resolveTTL(r.URL.Path, cacheManager.TTL())
+		if upstream.statusCode == http.StatusNotFound {
+			ttl = NEGATIVE_CACHE_TTL
+		}
+		cacheManager.Set(ctx, cacheKey, upstream.body, ttl)
 	}
 
 	// 返回响应
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(resp.StatusCode)
-	w.Write(body)
+	w.WriteHeader(upstream.statusCode)
+	w.Write(upstream.body)
+
+	logAccess(accessLogEntry{
+		requestID: requestID, method: r.Method, path: fullPath, clientIP: r.RemoteAddr,
+		cacheStatus: cacheStatusLabel(cacheManager.Enable(), CacheMiss), upstreamStatus: upstream.statusCode,
+		upstreamDuration: upstreamElapsed.Seconds(), totalDuration: time.Since(start).Seconds(),
+		bytes: len(upstream.body), authFingerprint: authFingerprint(r),
+	})
+	requestsTotal.WithLabelValues(strconv.Itoa(upstream.statusCode), cacheStatusLabel(cacheManager.Enable(), CacheMiss)).Inc()
+}
+
+// triggerBackgroundRefresh 为一次 stale 命中异步回源并更新缓存,
+// 通过 refreshGroup 去重,避免同一 stale key 的并发命中重复回源
+func triggerBackgroundRefresh(r *http.Request, cacheKey, fullPath string) {
+	go func() {
+		_, _, _ = refreshGroup.Do(cacheKey, func() (interface{}, error) {
+			tmdbURL := TMDB_BASE_URL + fullPath
+			upstream, err := fetchUpstreamWithRetry(context.Background(), r, tmdbURL)
+			if err != nil {
+				accessLogger.Warn("background refresh failed", "path", fullPath, "error", err.Error())
+				return nil, err
+			}
+			if cacheManager.IsAllow(r, upstream.statusCode, upstream.body) {
+				ttl := resolveTTL(r.URL.Path, cacheManager.TTL())
+				if upstream.statusCode == http.StatusNotFound {
+					ttl = NEGATIVE_CACHE_TTL
+				}
+				cacheManager.Set(context.Background(), cacheKey, upstream.body, ttl)
+				accessLogger.Info("background refresh stored", "path", fullPath)
+			}
+			return nil, nil
+		})
+	}()
+}
+
+// singleflightKey 基于请求 URI 和 Authorization header 的哈希构造去重 key,
+// 确保使用不同 API key 的客户端不会共享彼此的响应
+func singleflightKey(r *http.Request) string {
+	h := sha256.Sum256([]byte(r.Header.Get("Authorization")))
+	return r.URL.RequestURI() + "|" + hex.EncodeToString(h[:])
 }
 
 // 发送错误响应
@@ -219,12 +196,57 @@ func sendErrorResponse(w http.ResponseWriter, statusCode int, message string) {
 func main() {
 	// 解析命令行参数
 	port := flag.String("port", "8080", "Port to run the server on")
+	cacheBackend := flag.String("cache-backend", "memory", "Cache backend to use: memory, redis, none")
+	redisAddr := flag.String("redis-addr", "127.0.0.1:6379", "Redis address, used when -cache-backend=redis")
+	redisPassword := flag.String("redis-password", "", "Redis password, used when -cache-backend=redis")
+	redisDB := flag.Int("redis-db", 0, "Redis database index, used when -cache-backend=redis")
+	cacheDuration := flag.Duration("cache-duration", CACHE_DURATION, "Default cache TTL")
+	maxCacheSize := flag.Int("max-cache-size", MAX_CACHE_SIZE, "Maximum number of entries kept by the memory cache backend")
+	maxRetries := flag.Int("max-retries", MAX_RETRIES, "Maximum retry attempts against TMDB on network errors, 429 and 5xx")
+	rateLimit := flag.Float64("rate-limit", RATE_LIMIT, "Self-imposed outbound request rate limit (requests/sec) against TMDB")
+	configPath := flag.String("config", "", "Path to a YAML/JSON config file; its values override the flags above when set")
 	flag.Parse()
 
+	CACHE_DURATION = *cacheDuration
+	MAX_CACHE_SIZE = *maxCacheSize
+	MAX_RETRIES = *maxRetries
+	RATE_LIMIT = *rateLimit
+
+	settings := serverSettings{
+		port:          *port,
+		cacheBackend:  *cacheBackend,
+		redisAddr:     *redisAddr,
+		redisPassword: *redisPassword,
+		redisDB:       *redisDB,
+	}
+
+	if *configPath != "" {
+		cfg, err := LoadConfig(*configPath)
+		if err != nil {
+			log.Fatalf("load config: %v", err)
+		}
+		applyConfig(cfg, &settings)
+		go watchConfigReload(*configPath)
+	}
+
+	setUpstreamLimiter(rate.NewLimiter(rate.Limit(RATE_LIMIT), int(RATE_LIMIT)))
+	cacheManager = newCacheManager(settings.cacheBackend, settings.redisAddr, settings.redisPassword, settings.redisDB)
+
+	go reportCacheMetrics()
+
+	http.HandleFunc("/healthz", healthzHandler)
+	http.HandleFunc("/readyz", readyzHandler)
+	http.Handle("/metrics", promhttp.Handler())
 	http.HandleFunc("/", handler)
 
-	log.Printf("Server starting on port %s", *port)
-	if err := http.ListenAndServe(":"+*port, nil); err != nil {
+	log.Printf("Server starting on port %s", settings.port)
+	var err error
+	if settings.tlsCertFile != "" && settings.tlsKeyFile != "" {
+		err = http.ListenAndServeTLS(":"+settings.port, settings.tlsCertFile, settings.tlsKeyFile, nil)
+	} else {
+		err = http.ListenAndServe(":"+settings.port, nil)
+	}
+	if err != nil {
 		log.Fatal(err)
 	}
 }
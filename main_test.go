@@ -0,0 +1,25 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSingleflightKey(t *testing.T) {
+	r1 := httptest.NewRequest(http.MethodGet, "/3/movie/550", nil)
+	r1.Header.Set("Authorization", "Bearer token-a")
+
+	r2 := httptest.NewRequest(http.MethodGet, "/3/movie/550", nil)
+	r2.Header.Set("Authorization", "Bearer token-a")
+
+	r3 := httptest.NewRequest(http.MethodGet, "/3/movie/550", nil)
+	r3.Header.Set("Authorization", "Bearer token-b")
+
+	if singleflightKey(r1) != singleflightKey(r2) {
+		t.Fatal("expected identical requests to produce the same singleflight key")
+	}
+	if singleflightKey(r1) == singleflightKey(r3) {
+		t.Fatal("expected different auth headers to produce different singleflight keys")
+	}
+}
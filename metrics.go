@@ -0,0 +1,61 @@
+package main
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	requestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "tmdb_proxy_requests_total",
+		Help: "Total number of proxied requests, labeled by upstream status and cache status.",
+	}, []string{"status", "cache"})
+
+	upstreamDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "tmdb_proxy_upstream_duration_seconds",
+		Help:    "Latency of requests made to the TMDB upstream.",
+		Buckets: prometheus.DefBuckets,
+	})
+
+	cacheEntries = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "tmdb_proxy_cache_entries",
+		Help: "Number of entries currently held by the cache backend.",
+	})
+
+	cacheBytes = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "tmdb_proxy_cache_bytes",
+		Help: "Approximate number of bytes currently held by the cache backend.",
+	})
+
+	singleflightDedupTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "tmdb_proxy_singleflight_dedup_total",
+		Help: "Number of requests that were coalesced onto an in-flight upstream fetch instead of triggering a new one.",
+	})
+)
+
+// cacheEntryCounter 和 cacheByteCounter 是缓存后端可选实现的统计接口,
+// reportCacheMetrics 通过类型断言判断当前后端是否支持上报
+type cacheEntryCounter interface {
+	Len() int
+}
+
+type cacheByteCounter interface {
+	ApproxBytes() int64
+}
+
+// reportCacheMetrics 周期性地把缓存后端的条目数/字节数同步到 Prometheus gauge
+func reportCacheMetrics() {
+	ticker := time.NewTicker(15 * time.Second)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if counter, ok := cacheManager.(cacheEntryCounter); ok {
+			cacheEntries.Set(float64(counter.Len()))
+		}
+		if counter, ok := cacheManager.(cacheByteCounter); ok {
+			cacheBytes.Set(float64(counter.ApproxBytes()))
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// compiledRule 是一条规则连同它预编译出的匹配器,避免每次请求都重新编译 glob
+type compiledRule struct {
+	RuleConfig
+	re *regexp.Regexp
+}
+
+// activeRules 保存当前生效的按路径匹配的缓存规则,SIGHUP 重载配置时原子替换整个切片,
+// 因此读者始终看到一份完整一致的规则集,不会读到只更新了一半的状态
+var activeRules struct {
+	mu    sync.RWMutex
+	rules []compiledRule
+}
+
+// setRules 原子地替换当前生效的规则集,供启动时加载和 SIGHUP 热重载共用;
+// 调用方应已经用 compileRuleGlob 校验过每条规则的 glob,这里忽略编译失败的规则
+func setRules(rules []RuleConfig) {
+	compiled := make([]compiledRule, 0, len(rules))
+	for _, rule := range rules {
+		re, err := compileRuleGlob(rule.Pattern)
+		if err != nil {
+			continue
+		}
+		compiled = append(compiled, compiledRule{RuleConfig: rule, re: re})
+	}
+
+	activeRules.mu.Lock()
+	defer activeRules.mu.Unlock()
+	activeRules.rules = compiled
+}
+
+// compileRuleGlob 把规则里的 glob 模式编译成正则:"*" 匹配任意字符(含路径分隔符),
+// 这样 "/3/trending/*" 才能覆盖 /3/trending/movie/day 这类多段路径
+func compileRuleGlob(pattern string) (*regexp.Regexp, error) {
+	segments := strings.Split(pattern, "*")
+	for i, segment := range segments {
+		segments[i] = regexp.QuoteMeta(segment)
+	}
+
+	re, err := regexp.Compile("^" + strings.Join(segments, ".*") + "$")
+	if err != nil {
+		return nil, fmt.Errorf("compile glob %q: %w", pattern, err)
+	}
+	return re, nil
+}
+
+// matchRule 按声明顺序返回第一条匹配该路径的规则
+func matchRule(urlPath string) (RuleConfig, bool) {
+	activeRules.mu.RLock()
+	defer activeRules.mu.RUnlock()
+
+	for _, rule := range activeRules.rules {
+		if rule.re.MatchString(urlPath) {
+			return rule.RuleConfig, true
+		}
+	}
+	return RuleConfig{}, false
+}
+
+// resolveTTL 返回该路径应使用的缓存 TTL:命中规则则用规则的 TTL,否则退回默认值
+func resolveTTL(urlPath string, fallback time.Duration) time.Duration {
+	if rule, ok := matchRule(urlPath); ok && !rule.NoCache && rule.TTL > 0 {
+		return time.Duration(rule.TTL)
+	}
+	return fallback
+}
+
+// isNoCacheRule 判断该路径是否被规则显式标记为 no-cache,
+// no-cache 规则优先级最高,即使状态码本来允许缓存也要跳过
+func isNoCacheRule(urlPath string) bool {
+	rule, ok := matchRule(urlPath)
+	return ok && rule.NoCache
+}
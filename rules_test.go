@@ -0,0 +1,60 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMatchRule(t *testing.T) {
+	setRules([]RuleConfig{
+		{Pattern: "/3/configuration", TTL: Duration(24 * time.Hour)},
+		{Pattern: "/3/account/*", NoCache: true},
+	})
+	defer setRules(nil)
+
+	if _, ok := matchRule("/3/movie/1"); ok {
+		t.Error("matchRule(/3/movie/1) matched, want no match")
+	}
+
+	rule, ok := matchRule("/3/configuration")
+	if !ok || rule.TTL != Duration(24*time.Hour) {
+		t.Errorf("matchRule(/3/configuration) = %+v, %v", rule, ok)
+	}
+
+	rule, ok = matchRule("/3/account/14")
+	if !ok || !rule.NoCache {
+		t.Errorf("matchRule(/3/account/14) = %+v, %v", rule, ok)
+	}
+}
+
+func TestResolveTTL(t *testing.T) {
+	setRules([]RuleConfig{
+		{Pattern: "/3/trending/*", TTL: Duration(15 * time.Minute)},
+		{Pattern: "/3/account/*", NoCache: true},
+	})
+	defer setRules(nil)
+
+	if got := resolveTTL("/3/trending/movie/day", time.Hour); got != 15*time.Minute {
+		t.Errorf("resolveTTL(trending) = %v, want 15m", got)
+	}
+	if got := resolveTTL("/3/account/14", time.Hour); got != time.Hour {
+		t.Errorf("resolveTTL(account, no-cache rule) = %v, want fallback 1h", got)
+	}
+	if got := resolveTTL("/3/movie/1", time.Hour); got != time.Hour {
+		t.Errorf("resolveTTL(unmatched) = %v, want fallback 1h", got)
+	}
+}
+
+func TestIsNoCacheRule(t *testing.T) {
+	setRules([]RuleConfig{
+		{Pattern: "/3/account/*", NoCache: true},
+	})
+	defer setRules(nil)
+
+	if !isNoCacheRule("/3/account/14") {
+		t.Error("isNoCacheRule(/3/account/14) = false, want true")
+	}
+	if isNoCacheRule("/3/movie/1") {
+		t.Error("isNoCacheRule(/3/movie/1) = true, want false")
+	}
+}
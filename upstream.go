@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// RETRY_BASE_DELAY 是指数退避的基准延迟,实际延迟为 base * 2^attempt 再叠加抖动
+const RETRY_BASE_DELAY = 200 * time.Millisecond
+
+// NEGATIVE_CACHE_TTL 是 404 响应的短期缓存时长,用于抑制对已知不存在 ID 的重复回源
+const NEGATIVE_CACHE_TTL = 60 * time.Second
+
+// MAX_RETRIES 和 RATE_LIMIT 可通过 -max-retries / -rate-limit 覆盖,
+// UPSTREAM_TIMEOUT 只能通过配置文件的 upstream.timeout 覆盖
+var (
+	MAX_RETRIES      = 3
+	RATE_LIMIT       = 50.0 // 每秒请求数,对齐 TMDB 文档的限流建议
+	UPSTREAM_TIMEOUT = 30 * time.Second
+)
+
+// upstreamLimiter 是进程级令牌桶,在真正触发 TMDB 限流之前自我节流;
+// 用 atomic.Pointer 包装是因为 SIGHUP 重载会在请求处理 goroutine 之外替换它
+var upstreamLimiter atomic.Pointer[rate.Limiter]
+
+func init() {
+	upstreamLimiter.Store(rate.NewLimiter(rate.Limit(RATE_LIMIT), int(RATE_LIMIT)))
+}
+
+// setUpstreamLimiter 原子替换进程级令牌桶,供配置重载使用
+func setUpstreamLimiter(limiter *rate.Limiter) {
+	upstreamLimiter.Store(limiter)
+}
+
+// upstreamResult 保存一次 TMDB 回源的结果
+type upstreamResult struct {
+	statusCode int
+	body       []byte
+	header     http.Header
+}
+
+// isRetryableStatus 判断该状态码是否值得重试
+func isRetryableStatus(statusCode int) bool {
+	switch statusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// isCacheableStatus 判断该状态码对应的响应是否允许进入缓存;
+// 401/403/429/5xx 永远不缓存,避免把瞬时错误或鉴权失败当成正确答案存下来
+func isCacheableStatus(statusCode int) bool {
+	switch {
+	case statusCode == http.StatusOK:
+		return true
+	case statusCode == http.StatusNotFound:
+		return true
+	default:
+		return false
+	}
+}
+
+// fetchUpstreamWithRetry 在网络错误、429、502/503/504 时按指数退避重试,
+// 重试前会遵循 TMDB 返回的 Retry-After(若有),并始终先经过令牌桶自我限流。
+// ctx 由调用方传入而非取自 r.Context(),因为后台刷新发起时原始请求可能已经结束。
+// 请求体在进入重试循环前一次性读入内存,因为 r.Body 只能读一次,
+// POST 等带 body 的重试否则会在第二次尝试时静默发出空 body
+func fetchUpstreamWithRetry(ctx context.Context, r *http.Request, tmdbURL string) (*upstreamResult, error) {
+	var body []byte
+	if r.Body != nil {
+		var err error
+		body, err = io.ReadAll(r.Body)
+		if err != nil {
+			return nil, err
+		}
+		r.Body.Close()
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= MAX_RETRIES; attempt++ {
+		if err := upstreamLimiter.Load().Wait(ctx); err != nil {
+			return nil, err
+		}
+
+		result, err := fetchUpstream(ctx, r, tmdbURL, body)
+		if err != nil {
+			lastErr = err
+			if attempt == MAX_RETRIES {
+				break
+			}
+			log.Printf("Upstream error (attempt %d/%d): %v", attempt+1, MAX_RETRIES, err)
+			time.Sleep(backoffDelay(attempt, ""))
+			continue
+		}
+
+		if isRetryableStatus(result.statusCode) && attempt < MAX_RETRIES {
+			retryAfter := result.header.Get("Retry-After")
+			log.Printf("Upstream status %d (attempt %d/%d), retrying", result.statusCode, attempt+1, MAX_RETRIES)
+			time.Sleep(backoffDelay(attempt, retryAfter))
+			continue
+		}
+
+		return result, nil
+	}
+
+	return nil, lastErr
+}
+
+// fetchUpstream 向 TMDB 发起一次请求并读取响应体;body 由调用方预先读入内存,
+// 这里每次重试都重新包一个 Reader,避免复用已经被前一次尝试耗尽的流
+func fetchUpstream(ctx context.Context, r *http.Request, tmdbURL string, body []byte) (*upstreamResult, error) {
+	req, err := http.NewRequestWithContext(ctx, r.Method, tmdbURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	// 复制 Authorization header
+	if authHeader := r.Header.Get("Authorization"); authHeader != "" {
+		req.Header.Set("Authorization", authHeader)
+	}
+
+	client := &http.Client{
+		Timeout: UPSTREAM_TIMEOUT,
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return &upstreamResult{statusCode: resp.StatusCode, body: respBody, header: resp.Header}, nil
+}
+
+// backoffDelay 计算第 attempt 次重试前的等待时间:优先遵循 Retry-After,
+// 否则使用 base * 2^attempt 加上 ±50% 抖动的指数退避
+func backoffDelay(attempt int, retryAfter string) time.Duration {
+	if delay, ok := parseRetryAfter(retryAfter); ok {
+		return delay
+	}
+
+	backoff := RETRY_BASE_DELAY * time.Duration(1<<uint(attempt))
+	jitter := time.Duration(rand.Int63n(int64(backoff))) - backoff/2
+	return backoff + jitter
+}
+
+// parseRetryAfter 解析 Retry-After 头,支持 delta-seconds 和 HTTP-date 两种形式
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if t, err := http.ParseTime(value); err == nil {
+		delay := time.Until(t)
+		if delay < 0 {
+			delay = 0
+		}
+		return delay, true
+	}
+
+	return 0, false
+}
+
+// setRateLimitHeaders 把代理自身令牌桶的剩余额度暴露给调用方,
+// 便于客户端感知自己正在被代理限流而非 TMDB
+func setRateLimitHeaders(w http.ResponseWriter) {
+	limiter := upstreamLimiter.Load()
+	tokens := limiter.Tokens()
+	remaining := int(tokens)
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	reset := time.Duration(0)
+	if tokens < 1 {
+		reset = time.Duration((1 - tokens) / float64(limiter.Limit()) * float64(time.Second))
+	}
+
+	w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+	w.Header().Set("X-RateLimit-Reset", strconv.Itoa(int(reset.Seconds())))
+}
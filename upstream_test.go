@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfterDeltaSeconds(t *testing.T) {
+	delay, ok := parseRetryAfter("120")
+	if !ok {
+		t.Fatal("expected delta-seconds form to parse")
+	}
+	if delay != 120*time.Second {
+		t.Fatalf("expected 120s, got %s", delay)
+	}
+}
+
+func TestParseRetryAfterHTTPDate(t *testing.T) {
+	future := time.Now().Add(30 * time.Second).UTC()
+	delay, ok := parseRetryAfter(future.Format(http.TimeFormat))
+	if !ok {
+		t.Fatal("expected HTTP-date form to parse")
+	}
+	if delay <= 0 || delay > 31*time.Second {
+		t.Fatalf("expected delay close to 30s, got %s", delay)
+	}
+}
+
+func TestParseRetryAfterInvalid(t *testing.T) {
+	if _, ok := parseRetryAfter("not-a-valid-value"); ok {
+		t.Fatal("expected invalid Retry-After to fail parsing")
+	}
+	if _, ok := parseRetryAfter(""); ok {
+		t.Fatal("expected empty Retry-After to fail parsing")
+	}
+}
+
+func TestIsRetryableStatus(t *testing.T) {
+	retryable := []int{http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout}
+	for _, status := range retryable {
+		if !isRetryableStatus(status) {
+			t.Fatalf("expected status %d to be retryable", status)
+		}
+	}
+
+	nonRetryable := []int{http.StatusOK, http.StatusNotFound, http.StatusUnauthorized, http.StatusForbidden}
+	for _, status := range nonRetryable {
+		if isRetryableStatus(status) {
+			t.Fatalf("expected status %d to not be retryable", status)
+		}
+	}
+}
+
+// TestFetchUpstreamWithRetryReplaysBody 确认一次 503 重试后,第二次尝试
+// 仍然带上原始请求体,而不是因为 r.Body 已被首次尝试耗尽而发出空 body
+func TestFetchUpstreamWithRetryReplaysBody(t *testing.T) {
+	origMaxRetries := MAX_RETRIES
+	MAX_RETRIES = 1
+	defer func() { MAX_RETRIES = origMaxRetries }()
+
+	var receivedBodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		receivedBodies = append(receivedBodies, string(body))
+		if len(receivedBodies) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	r := httptest.NewRequest(http.MethodPost, "/3/account/1/rating", strings.NewReader("payload"))
+	result, err := fetchUpstreamWithRetry(context.Background(), r, server.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.statusCode != http.StatusOK {
+		t.Fatalf("expected eventual 200, got %d", result.statusCode)
+	}
+	if len(receivedBodies) != 2 {
+		t.Fatalf("expected 2 upstream attempts, got %d", len(receivedBodies))
+	}
+	for i, body := range receivedBodies {
+		if body != "payload" {
+			t.Fatalf("attempt %d: expected body to be replayed as %q, got %q", i+1, "payload", body)
+		}
+	}
+}
+
+func TestIsCacheableStatus(t *testing.T) {
+	cacheable := []int{http.StatusOK, http.StatusNotFound}
+	for _, status := range cacheable {
+		if !isCacheableStatus(status) {
+			t.Fatalf("expected status %d to be cacheable", status)
+		}
+	}
+
+	uncacheable := []int{http.StatusUnauthorized, http.StatusForbidden, http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway}
+	for _, status := range uncacheable {
+		if isCacheableStatus(status) {
+			t.Fatalf("expected status %d to never be cacheable", status)
+		}
+	}
+}